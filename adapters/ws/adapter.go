@@ -0,0 +1,218 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+
+	"shirobot2/core"
+)
+
+// Adapter 基于WebSocket的core.Adapter实现,每条连接收到的帧按JSON-RPC 2.0解析,
+// 并转换为携带 `/method arg1 arg2 ...` 文本的core.Event,复用既有的
+// EventProcessor.ParseCommand解析路径
+type Adapter struct {
+	name   string
+	addr   string
+	logger *slog.Logger
+
+	upgrader websocket.Upgrader
+	srv      *http.Server
+
+	conns  map[string]*conn
+	connMu sync.RWMutex
+	seq    uint64
+
+	connWG sync.WaitGroup // 跟踪所有仍在运行的handleConn,Start必须等它们都退出才能返回
+}
+
+// NewAdapter 创建一个监听addr的WebSocket适配器
+func NewAdapter(name, addr string, logger *slog.Logger) *Adapter {
+	return &Adapter{
+		name:     name,
+		addr:     addr,
+		logger:   logger,
+		upgrader: websocket.Upgrader{},
+		conns:    make(map[string]*conn),
+	}
+}
+
+func (a *Adapter) Name() string {
+	return a.name
+}
+
+// Start 启动HTTP服务器并接受WebSocket连接,每个连接上收到的帧都会被
+// 翻译为core.Event送入eventChan。ctx被取消后,除了关闭HTTP服务器本身,
+// 还会显式断开所有已升级的WebSocket连接并等待它们的处理协程全部退出后才返回——
+// http.Server.Close不会感知/处理已hijack的连接,不主动断开它们就会在关闭期间
+// 残留还在运行的handleConn,可能并发地往已经被调用方关闭的eventChan上发送
+func (a *Adapter) Start(ctx context.Context, eventChan chan<- core.Event) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		wsConn, err := a.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			a.logger.Error("[adapter.ws] upgrade failed", "error", err)
+			return
+		}
+		a.connWG.Add(1)
+		defer a.connWG.Done()
+		a.handleConn(ctx, wsConn, eventChan)
+	})
+
+	a.srv = &http.Server{Addr: a.addr, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		_ = a.srv.Close()
+		a.closeConns()
+	}()
+
+	a.logger.Debug("[adapter.ws] listening", "addr", a.addr)
+	err := a.srv.ListenAndServe()
+	a.connWG.Wait()
+	if err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("ws adapter: listen %s: %w", a.addr, err)
+	}
+	return nil
+}
+
+// closeConns 主动断开所有当前连接,促使它们的ReadMessage循环返回并退出handleConn
+func (a *Adapter) closeConns() {
+	a.connMu.RLock()
+	defer a.connMu.RUnlock()
+	for _, c := range a.conns {
+		_ = c.ws.Close()
+	}
+}
+
+func (a *Adapter) nextConnID() string {
+	id := atomic.AddUint64(&a.seq, 1)
+	return fmt.Sprintf("%s-%d", a.name, id)
+}
+
+func (a *Adapter) handleConn(ctx context.Context, wsConn *websocket.Conn, eventChan chan<- core.Event) {
+	connID := a.nextConnID()
+	c := newConn(connID, wsConn)
+
+	a.connMu.Lock()
+	a.conns[connID] = c
+	a.connMu.Unlock()
+
+	go c.writeLoop()
+
+	defer func() {
+		a.connMu.Lock()
+		delete(a.conns, connID)
+		a.connMu.Unlock()
+		c.closeSend()
+		_ = wsConn.Close()
+	}()
+
+	session := &core.Session{ID: connID}
+
+	for {
+		_, raw, err := wsConn.ReadMessage()
+		if err != nil {
+			a.logger.Debug("[adapter.ws] connection closed", "conn_id", connID, "error", err)
+			return
+		}
+
+		var req Request
+		if err := json.Unmarshal(raw, &req); err != nil {
+			a.writeFrame(c, newErrorResponse(nil, rpcParseError, "parse error"))
+			continue
+		}
+		if req.JSONRPC != "2.0" || req.Method == "" {
+			a.writeFrame(c, newErrorResponse(req.ID, rpcInvalidRequest, "invalid request"))
+			continue
+		}
+
+		reqKey := c.nextReqKey()
+		if !req.IsNotification() {
+			c.pushPending(reqKey, req.ID)
+		}
+
+		text := "/" + strings.ReplaceAll(req.Method, "/", " ")
+		if args := paramsToArgs(req.Params); len(args) > 0 {
+			text += " " + strings.Join(args, " ")
+		}
+
+		select {
+		case eventChan <- core.Event{
+			Type:        core.EventTypeMessage,
+			Platform:    a.name,
+			Data:        map[string]interface{}{"text": text},
+			Session:     session,
+			AdapterName: a.name,
+			RequestID:   reqKey,
+		}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// SendResponse 实现core.Adapter接口。引擎已按Event.AdapterName把响应路由回本适配器,
+// 这里只需按resp.Metadata["conn_id"]找到对应连接;连接不存在(已断开)时直接忽略
+func (a *Adapter) SendResponse(ctx context.Context, resp core.Response) error {
+	connID := resp.Metadata["conn_id"]
+	if connID == "" {
+		return nil
+	}
+
+	a.connMu.RLock()
+	c, ok := a.conns[connID]
+	a.connMu.RUnlock()
+	if !ok {
+		a.logger.Debug("[adapter.ws] response for unknown connection dropped", "conn_id", connID)
+		return nil
+	}
+
+	// 无关联请求id的服务端主动通知,以JSON-RPC通知帧(无id)下发
+	if method := resp.Metadata["notify_method"]; method != "" {
+		return a.writeFrame(c, &notification{JSONRPC: "2.0", Method: method, Params: resp.Data})
+	}
+
+	// 按请求key关联原始JSON-RPC id,而非按到达顺序FIFO弹出,避免并发请求/
+	// 流式分片错配到其他请求。流式响应的中间分片(final=false)只读取id、
+	// 不移除,最终分片到达时才真正释放这个请求key
+	reqKey := resp.Metadata["request_id"]
+	var id json.RawMessage
+	var found bool
+	if resp.Metadata["final"] == "false" {
+		id, found = c.peekPending(reqKey)
+	} else {
+		id, found = c.popPending(reqKey)
+	}
+	if !found {
+		a.logger.Debug("[adapter.ws] response for unknown or already-completed request dropped", "request_id", reqKey)
+		return nil
+	}
+
+	if resp.Type == core.ResponseTypeError {
+		return a.writeFrame(c, newErrorResponse(id, rpcInternalError, fmt.Sprintf("%v", resp.Data)))
+	}
+	return a.writeFrame(c, newResultResponse(id, resp.Data))
+}
+
+func (a *Adapter) writeFrame(c *conn, frame interface{}) error {
+	b, err := json.Marshal(frame)
+	if err != nil {
+		return fmt.Errorf("ws adapter: marshal frame: %w", err)
+	}
+	return c.send(b)
+}
+
+// notification 服务端主动推送,无id字段
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
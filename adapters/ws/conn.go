@@ -0,0 +1,92 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+)
+
+// conn 封装单条 WebSocket 连接及其会话状态
+type conn struct {
+	id     string
+	ws     *websocket.Conn
+	sendCh chan []byte
+
+	mu         sync.Mutex
+	reqSeq     uint64
+	pending    map[string]json.RawMessage // 按请求key关联的未响应请求id,而非按到达顺序
+	sendClosed bool                       // sendCh是否已关闭,与写入共用mu,避免并发SendResponse和断线清理竞争
+}
+
+func newConn(id string, wsConn *websocket.Conn) *conn {
+	return &conn{
+		id:      id,
+		ws:      wsConn,
+		sendCh:  make(chan []byte, 64),
+		pending: make(map[string]json.RawMessage),
+	}
+}
+
+// send 把一帧数据投递给写协程。连接已断开(sendCh已关闭)时返回error而不是panic
+func (c *conn) send(b []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sendClosed {
+		return fmt.Errorf("ws conn: %s already closed", c.id)
+	}
+	c.sendCh <- b
+	return nil
+}
+
+// closeSend 关闭写通道,之后的send调用都会直接返回error而不是向已关闭的channel发送
+func (c *conn) closeSend() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sendClosed {
+		return
+	}
+	c.sendClosed = true
+	close(c.sendCh)
+}
+
+// nextReqKey 为本连接上的一次请求生成唯一key,独立于客户端传入的JSON-RPC id,
+// 用于把该请求流经引擎期间产生的所有响应(含流式分片)关联回同一条请求
+func (c *conn) nextReqKey() string {
+	seq := atomic.AddUint64(&c.reqSeq, 1)
+	return fmt.Sprintf("%s-%d", c.id, seq)
+}
+
+// pushPending 记录一次需要响应的请求id,以请求key索引
+func (c *conn) pushPending(key string, id json.RawMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending[key] = id
+}
+
+// peekPending 按请求key查找原始请求id但不移除,供流式响应的中间分片复用同一个id
+func (c *conn) peekPending(key string) (json.RawMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id, ok := c.pending[key]
+	return id, ok
+}
+
+// popPending 按请求key取出并移除原始请求id,在该请求的最终响应发出时调用
+func (c *conn) popPending(key string) (json.RawMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id, ok := c.pending[key]
+	delete(c.pending, key)
+	return id, ok
+}
+
+func (c *conn) writeLoop() {
+	for msg := range c.sendCh {
+		if err := c.ws.WriteMessage(websocket.TextMessage, msg); err != nil {
+			return
+		}
+	}
+}
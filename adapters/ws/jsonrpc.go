@@ -0,0 +1,86 @@
+package ws
+
+import "encoding/json"
+
+// JSON-RPC 2.0 错误码 (参照规范保留区间)
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+)
+
+// Request JSON-RPC 2.0 请求帧
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// IsNotification 判断请求是否为通知(无id,不需要响应)
+func (r *Request) IsNotification() bool {
+	return len(r.ID) == 0
+}
+
+// rpcError JSON-RPC 2.0 错误对象
+type rpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Response JSON-RPC 2.0 响应帧
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+func newResultResponse(id json.RawMessage, result interface{}) *Response {
+	return &Response{JSONRPC: "2.0", ID: id, Result: result}
+}
+
+func newErrorResponse(id json.RawMessage, code int, message string) *Response {
+	return &Response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}}
+}
+
+// paramsToArgs 把 JSON-RPC params(数组或对象)拍平成命令行式参数,
+// 以便套用既有的 EventProcessor.ParseCommand(`/method arg1 arg2`)解析路径
+func paramsToArgs(params json.RawMessage) []string {
+	if len(params) == 0 {
+		return nil
+	}
+
+	var arr []interface{}
+	if err := json.Unmarshal(params, &arr); err == nil {
+		args := make([]string, 0, len(arr))
+		for _, v := range arr {
+			args = append(args, toArg(v))
+		}
+		return args
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal(params, &obj); err == nil {
+		args := make([]string, 0, len(obj))
+		for k, v := range obj {
+			args = append(args, k+"="+toArg(v))
+		}
+		return args
+	}
+
+	return []string{string(params)}
+}
+
+func toArg(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	default:
+		b, _ := json.Marshal(t)
+		return string(b)
+	}
+}
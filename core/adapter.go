@@ -17,7 +17,7 @@ type Adapter interface {
 type AdapterManager struct {
 	logger *slog.Logger
 
-	adapters []Adapter
+	adapters map[string]Adapter
 	mu       sync.RWMutex
 }
 
@@ -25,19 +25,31 @@ func NewManager(logger *slog.Logger) *AdapterManager {
 	return &AdapterManager{
 		logger: logger,
 
-		adapters: make([]Adapter, 0),
-		mu:       sync.RWMutex{},
+		adapters: make(map[string]Adapter),
 	}
 }
 
 func (am *AdapterManager) Register(adapter Adapter) {
 	am.mu.Lock()
 	defer am.mu.Unlock()
-	am.adapters = append(am.adapters, adapter)
+	am.adapters[adapter.Name()] = adapter
+}
+
+// Get 按名称查找已注册的适配器,用于把响应路由回产生对应事件的那一个适配器
+func (am *AdapterManager) Get(name string) (Adapter, bool) {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+	adapter, ok := am.adapters[name]
+	return adapter, ok
 }
 
 func (am *AdapterManager) GetAll() []Adapter {
 	am.mu.RLock()
 	defer am.mu.RUnlock()
-	return am.adapters
+
+	adapters := make([]Adapter, 0, len(am.adapters))
+	for _, a := range am.adapters {
+		adapters = append(adapters, a)
+	}
+	return adapters
 }
@@ -0,0 +1,23 @@
+// Package bus 定义跨实例事件/响应分发的总线抽象,
+// 使shirobot2既能以单实例方式运行,也能以共享broker的多副本方式水平扩展
+package bus
+
+import "context"
+
+// Msg 从总线收到的一条消息
+type Msg struct {
+	Topic   string
+	Payload []byte
+
+	// Ack 确认该消息已被成功处理。内存实现为no-op,
+	// Redis Streams实现对应一次XACK,避免重复投递
+	Ack func(ctx context.Context) error
+}
+
+// Bus 事件/响应总线。多个实例订阅同一topic时,具体的扇出/抢占语义
+// 由实现决定:内存实现广播给所有订阅者,Redis Streams实现通过消费组
+// 保证一条消息仅被组内一个实例处理
+type Bus interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+	Subscribe(ctx context.Context, topic string) (<-chan Msg, error)
+}
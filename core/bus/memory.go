@@ -0,0 +1,97 @@
+package bus
+
+import (
+	"context"
+	"sync"
+)
+
+// subscriber 包装一个订阅者的投递通道。Publish(写入)和ctx取消后的清理(关闭)
+// 都要经过同一把锁,避免Publish往一个正在被并发Close的通道上发送导致panic
+type subscriber struct {
+	mu     sync.Mutex
+	ch     chan Msg
+	closed bool
+}
+
+func (s *subscriber) send(ctx context.Context, msg Msg) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.ch <- msg:
+	case <-ctx.Done():
+	}
+}
+
+func (s *subscriber) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.ch)
+}
+
+// MemoryBus 进程内总线,按topic把消息广播给所有订阅者,是单实例部署下的默认实现
+type MemoryBus struct {
+	mu   sync.RWMutex
+	subs map[string][]*subscriber
+}
+
+// NewMemoryBus 创建一个进程内总线
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{subs: make(map[string][]*subscriber)}
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, topic string, payload []byte) error {
+	b.mu.RLock()
+	subs := append([]*subscriber(nil), b.subs[topic]...)
+	b.mu.RUnlock()
+
+	msg := Msg{
+		Topic:   topic,
+		Payload: payload,
+		Ack:     func(context.Context) error { return nil },
+	}
+
+	for _, sub := range subs {
+		sub.send(ctx, msg)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(ctx context.Context, topic string) (<-chan Msg, error) {
+	sub := &subscriber{ch: make(chan Msg, 64)}
+
+	b.mu.Lock()
+	b.subs[topic] = append(b.subs[topic], sub)
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.unsubscribe(topic, sub)
+		sub.close()
+	}()
+
+	return sub.ch, nil
+}
+
+func (b *MemoryBus) unsubscribe(topic string, sub *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subs := b.subs[topic]
+	for i, s := range subs {
+		if s == sub {
+			b.subs[topic] = append(subs[:i], subs[i+1:]...)
+			return
+		}
+	}
+}
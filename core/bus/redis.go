@@ -0,0 +1,147 @@
+package bus
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// claimInterval 定期扫描pending列表、回收卡住消息的频率
+	claimInterval = 30 * time.Second
+	// claimMinIdle 消息在pending列表中停留多久后才允许被其他consumer抢占
+	claimMinIdle = time.Minute
+	// readBlock XReadGroup单次阻塞等待新消息的时长
+	readBlock = 5 * time.Second
+)
+
+// RedisBus 基于Redis Streams的跨实例总线。每个shirobot2实例以自己的instanceID
+// 作为consumer名称加入同一个消费组,保证一条消息只会被组内一个实例处理;
+// 处理完成后需调用Msg.Ack做XACK,未确认的消息会被reclaimLoop通过XCLAIM转交出去
+type RedisBus struct {
+	client   *redis.Client
+	group    string
+	consumer string
+}
+
+// NewRedisBus 创建一个Redis Streams总线,group为消费组名,instanceID为本实例的consumer名
+func NewRedisBus(client *redis.Client, group, instanceID string) *RedisBus {
+	return &RedisBus{client: client, group: group, consumer: instanceID}
+}
+
+func (b *RedisBus) Publish(ctx context.Context, topic string, payload []byte) error {
+	err := b.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: topic,
+		Values: map[string]interface{}{"payload": payload},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("bus: xadd %s: %w", topic, err)
+	}
+	return nil
+}
+
+func (b *RedisBus) Subscribe(ctx context.Context, topic string) (<-chan Msg, error) {
+	if err := b.ensureGroup(ctx, topic); err != nil {
+		return nil, err
+	}
+
+	out := make(chan Msg, 64)
+
+	// consume和reclaimLoop都会向out投递消息,close(out)必须等二者都退出后
+	// 由唯一的owner执行,否则任意一方可能在另一方仍在写入时把channel关闭导致panic
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); b.consume(ctx, topic, out) }()
+	go func() { defer wg.Done(); b.reclaimLoop(ctx, topic, out) }()
+	go func() { wg.Wait(); close(out) }()
+
+	return out, nil
+}
+
+func (b *RedisBus) ensureGroup(ctx context.Context, topic string) error {
+	err := b.client.XGroupCreateMkStream(ctx, topic, b.group, "$").Err()
+	if err != nil && !strings.Contains(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("bus: create group %s/%s: %w", topic, b.group, err)
+	}
+	return nil
+}
+
+func (b *RedisBus) consume(ctx context.Context, topic string, out chan<- Msg) {
+	for ctx.Err() == nil {
+		streams, err := b.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    b.group,
+			Consumer: b.consumer,
+			Streams:  []string{topic, ">"},
+			Count:    32,
+			Block:    readBlock,
+		}).Result()
+		if err != nil {
+			if err != redis.Nil && ctx.Err() == nil {
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
+		for _, stream := range streams {
+			for _, m := range stream.Messages {
+				b.deliver(ctx, topic, m, out)
+			}
+		}
+	}
+}
+
+func (b *RedisBus) deliver(ctx context.Context, topic string, m redis.XMessage, out chan<- Msg) {
+	payload, _ := m.Values["payload"].(string)
+	id := m.ID
+
+	msg := Msg{
+		Topic:   topic,
+		Payload: []byte(payload),
+		Ack: func(ctx context.Context) error {
+			return b.client.XAck(ctx, topic, b.group, id).Err()
+		},
+	}
+
+	select {
+	case out <- msg:
+	case <-ctx.Done():
+	}
+}
+
+// reclaimLoop 周期性地把闲置超过claimMinIdle的pending消息通过XAutoClaim转交给本实例,
+// 避免某个副本崩溃后消息永远停留在pending列表里
+func (b *RedisBus) reclaimLoop(ctx context.Context, topic string, out chan<- Msg) {
+	ticker := time.NewTicker(claimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.reclaimPending(ctx, topic, out)
+		}
+	}
+}
+
+func (b *RedisBus) reclaimPending(ctx context.Context, topic string, out chan<- Msg) {
+	messages, _, err := b.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   topic,
+		Group:    b.group,
+		Consumer: b.consumer,
+		MinIdle:  claimMinIdle,
+		Start:    "0",
+		Count:    32,
+	}).Result()
+	if err != nil {
+		return
+	}
+
+	for _, m := range messages {
+		b.deliver(ctx, topic, m, out)
+	}
+}
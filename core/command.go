@@ -3,8 +3,10 @@ package core
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 type Command struct {
@@ -15,6 +17,7 @@ type Command struct {
 	Description string
 	Usage       string
 	Handler     CommandHandler
+	Streaming   bool // 为true时命令预期通过Context.Send多次下发响应
 
 	commands        map[string]*Command // 子命令(可嵌套)
 	commandsAliases map[string]string   // 子命令别名
@@ -101,4 +104,48 @@ type Context struct {
 	// User     User
 	Session *Session
 	Command *Command
+
+	stream   chan *Response // 流式响应通道,由EventProcessor在调度命令时创建
+	streamID string
+	seq      *int64
+	streamed *int32 // 原子标记: 非0表示本次请求中曾调用过Send
+}
+
+// Stream 返回流式响应通道的写端,供handler直接写入原始*Response使用
+func (c Context) Stream() chan<- *Response {
+	return c.stream
+}
+
+// Send 向流式通道发送一条响应,自动附加stream_id/seq关联元数据,
+// 用于渐进式输出(如LLM流式生成、长命令tail)。调用Send会将本次
+// 请求标记为流式,EventProcessor随后会补发一条final标记帧收尾。
+// 通道已满时阻塞等待空位,直到ctx被取消为止(此时返回ctx.Err())
+func (c Context) Send(resp *Response) error {
+	atomic.StoreInt32(c.streamed, 1)
+
+	if resp.Metadata == nil {
+		resp.Metadata = make(map[string]string)
+	}
+	resp.Metadata["stream_id"] = c.streamID
+	resp.Metadata["seq"] = strconv.FormatInt(atomic.AddInt64(c.seq, 1), 10)
+	resp.Metadata["final"] = "false"
+
+	select {
+	case c.stream <- resp:
+		return nil
+	case <-c.ctx.Done():
+		return c.ctx.Err()
+	}
+}
+
+// Context 返回该请求携带的context.Context
+func (c Context) Context() context.Context {
+	return c.ctx
+}
+
+// WithContext 返回一份使用给定context.Context的Context副本,
+// 供超时/取消等中间件替换底层context后继续向下传递
+func (c Context) WithContext(ctx context.Context) Context {
+	c.ctx = ctx
+	return c
 }
@@ -2,8 +2,24 @@ package core
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log/slog"
+	"os"
 	"sync"
+	"time"
+
+	"shirobot2/core/bus"
+	"shirobot2/core/session"
+)
+
+const (
+	// eventsTopic 所有实例共享的事件总线topic
+	eventsTopic = "shirobot2.events"
+	// responseTopicPrefix 按实例隔离的响应topic前缀,避免跨实例广播
+	responseTopicPrefix = "shirobot2.responses."
+	// shutdownDrainTimeout 关闭期间继续消费总线上残留事件/响应的最长等待时间
+	shutdownDrainTimeout = 5 * time.Second
 )
 
 // Engine 核心引擎协调各组件工作
@@ -15,12 +31,17 @@ type Engine struct {
 	processor  *EventProcessor     // 事件处理器
 	dispatcher *ResponseDispatcher // 响应分发器
 
-	eventChan    chan Event    // 事件通道
-	responseChan chan Response // 响应通道
+	instanceID string        // 本实例标识,用于多实例场景下的响应路由
+	bus        bus.Bus       // 跨实例事件/响应总线
+	sessions   session.Store // 会话存储
+
+	eventChan    chan Event    // 本地事件暂存通道,由publishEvents转发到总线
+	responseChan chan Response // 本地响应暂存通道,由publishResponses转发到总线
+
+	eventPool  sync.Pool   // 事件池
+	workerPool *WorkerPool // 工作池
 
-	sessionPool sync.Pool   // 会话池
-	eventPool   sync.Pool   // 事件池
-	workerPool  *WorkerPool // 工作池
+	dispatchWG sync.WaitGroup // 跟踪handleResponseMsg发出的go e.dispatcher.Dispatch协程,关闭适配器前必须等待它们结束
 }
 
 type EngineConfig struct {
@@ -28,6 +49,10 @@ type EngineConfig struct {
 	EventChanSize    int
 	ResponseChanSize int
 	WorkerPoolSize   int
+
+	InstanceID   string        // 实例标识,默认按主机名+pid生成
+	Bus          bus.Bus       // 事件/响应总线,默认进程内实现(单实例)
+	SessionStore session.Store // 会话存储,默认进程内实现(单实例)
 }
 
 // EngineOption 引擎配置选项
@@ -61,58 +86,98 @@ func WithWorkerPoolSize(size int) EngineOption {
 	}
 }
 
-// 全局单例
+// WithInstanceID 配置本实例的唯一标识,多实例部署下必须保证各实例不同
+func WithInstanceID(id string) EngineOption {
+	return func(e *EngineConfig) {
+		e.InstanceID = id
+	}
+}
+
+// WithBus 配置跨实例事件/响应总线,多实例部署下应传入共享的broker实现(如RedisBus)
+func WithBus(b bus.Bus) EngineOption {
+	return func(e *EngineConfig) {
+		e.Bus = b
+	}
+}
+
+// WithSessionStore 配置会话存储,多实例部署下应传入共享的存储实现(如RedisStore)
+func WithSessionStore(store session.Store) EngineOption {
+	return func(e *EngineConfig) {
+		e.SessionStore = store
+	}
+}
+
+func defaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// 进程内的全局默认引擎,供不关心多实例、只想要一个共享Engine的调用方使用。
+// 与NewEngine解耦: NewEngine总是返回一个独立的新实例,只有显式调用SetDefault
+// 才会把某个实例设为全局默认
 var (
-	_Engine *Engine
-	once    sync.Once
+	_Engine  *Engine
+	engineMu sync.RWMutex
 )
 
-// NewEngine 创建新引擎实例
+// NewEngine 创建一个全新的引擎实例,每次调用都会应用传入的opts
 func NewEngine(opts ...EngineOption) *Engine {
-	once.Do(func() {
-
-		// 默认配置
-		ecfg := &EngineConfig{
-			Logger:           slog.Default(),
-			EventChanSize:    1000, // 默认事件通道大小1000
-			ResponseChanSize: 1000, // 默认响应通道大小1000
-			WorkerPoolSize:   100,  // 默认工作池大小100
-		}
+	// 默认配置
+	ecfg := &EngineConfig{
+		Logger:           slog.Default(),
+		EventChanSize:    1000, // 默认事件通道大小1000
+		ResponseChanSize: 1000, // 默认响应通道大小1000
+		WorkerPoolSize:   100,  // 默认工作池大小100
+		InstanceID:       defaultInstanceID(),
+		Bus:              bus.NewMemoryBus(),
+		SessionStore:     session.NewMemoryStore(),
+	}
 
-		// 应用配置
-		for _, opt := range opts {
-			opt(ecfg)
-		}
+	// 应用配置
+	for _, opt := range opts {
+		opt(ecfg)
+	}
 
-		e := &Engine{}
+	e := &Engine{}
 
-		e.logger = ecfg.Logger
+	e.logger = ecfg.Logger
 
-		e.adapters = NewManager(e.logger)
-		e.plugins = NewPluginManager(e.logger)
-		e.processor = NewEventProcessor(e.logger, e.plugins)
-		e.dispatcher = NewResponseDispatcher(e.logger, e.adapters)
+	e.adapters = NewManager(e.logger)
+	e.plugins = NewPluginManager(e.logger)
+	e.processor = NewEventProcessor(e.logger, e.plugins)
+	e.dispatcher = NewResponseDispatcher(e.logger, e.adapters)
 
-		e.eventChan = make(chan Event, ecfg.EventChanSize)
-		e.responseChan = make(chan Response, ecfg.ResponseChanSize)
+	e.instanceID = ecfg.InstanceID
+	e.bus = ecfg.Bus
+	e.sessions = ecfg.SessionStore
 
-		e.sessionPool = sync.Pool{
-			New: func() interface{} { return &Session{} },
-		}
-		e.eventPool = sync.Pool{
-			New: func() interface{} { return &Event{} },
-		}
-		e.workerPool = NewWorkerPool(ecfg.WorkerPoolSize)
+	e.eventChan = make(chan Event, ecfg.EventChanSize)
+	e.responseChan = make(chan Response, ecfg.ResponseChanSize)
 
-		e.logger.Debug("[engine] engine created.", "config", ecfg)
+	e.eventPool = sync.Pool{
+		New: func() interface{} { return &Event{} },
+	}
+	e.workerPool = NewWorkerPool(ecfg.WorkerPoolSize)
 
-		_Engine = e
-	})
+	e.logger.Debug("[engine] engine created.", "config", ecfg)
 
-	return _Engine
+	return e
+}
+
+// SetDefault 把e设为GetEngine()返回的全局默认引擎
+func SetDefault(e *Engine) {
+	engineMu.Lock()
+	defer engineMu.Unlock()
+	_Engine = e
 }
 
+// GetEngine 返回通过SetDefault设置的全局默认引擎,未设置时返回nil
 func GetEngine() *Engine {
+	engineMu.RLock()
+	defer engineMu.RUnlock()
 	return _Engine
 }
 
@@ -124,38 +189,228 @@ func (e *Engine) RegisterPlugin(plugin Plugin, mws ...Middleware) {
 	e.plugins.Register(plugin, mws...)
 }
 
-// Run 启动引擎主循环
+// Use 注册引擎级中间件,包裹每一次命令调度(顺序: 引擎级 -> 插件级 -> 命令级)
+func (e *Engine) Use(mws ...Middleware) {
+	e.processor.Use(mws...)
+}
+
+// Run 启动引擎主循环,直到ctx被取消后按顺序优雅关闭: 先停适配器,
+// 再排空事件/响应,再停worker池,最后关闭内部通道
 func (e *Engine) Run(ctx context.Context) error {
-	defer e.cleanup()
-	e.logger.Debug("[engine] engine starting...")
+	e.logger.Debug("[engine] engine starting...", "instance_id", e.instanceID)
 
-	// 启动所有适配器
+	// 适配器使用独立的context,使其生命周期完全由本函数的关闭时序控制,
+	// 而不是在ctx被取消的瞬间就和主循环同时退出
+	adapterCtx, cancelAdapters := context.WithCancel(context.Background())
+	var adapterWG sync.WaitGroup
 	for _, adapter := range e.adapters.GetAll() {
+		adapterWG.Add(1)
 		go func(a Adapter) {
-			if err := a.Start(ctx, e.eventChan); err != nil {
+			defer adapterWG.Done()
+			if err := a.Start(adapterCtx, e.eventChan); err != nil {
 				e.handleError(err)
 			}
-			e.logger.Debug("[engine] adapter started.", "adapter", a.Name())
+			e.logger.Debug("[engine] adapter stopped.", "adapter", a.Name())
 		}(adapter)
 	}
 
-	// 启动worker池
 	e.workerPool.Start()
 	e.logger.Debug("[engine] worker pool started.")
 
-	// 主事件循环
+	// 总线订阅同样使用独立的context,以便关闭期间继续排空eventChan/responseChan
+	// 转发出去的消息,而不是随主循环一起立刻失效
+	busCtx, cancelBus := context.WithCancel(context.Background())
+
+	events, err := e.bus.Subscribe(busCtx, eventsTopic)
+	if err != nil {
+		cancelAdapters()
+		cancelBus()
+		return fmt.Errorf("engine: subscribe events: %w", err)
+	}
+	responses, err := e.bus.Subscribe(busCtx, e.responseTopic())
+	if err != nil {
+		cancelAdapters()
+		cancelBus()
+		return fmt.Errorf("engine: subscribe responses: %w", err)
+	}
+
+	var busWG sync.WaitGroup
+	busWG.Add(2)
+	go func() { defer busWG.Done(); e.publishEvents(busCtx) }()
+	go func() { defer busWG.Done(); e.publishResponses(busCtx) }()
+
+	e.loop(ctx, events, responses)
+	e.shutdown(cancelAdapters, &adapterWG, cancelBus, &busWG, events, responses)
+
+	return ctx.Err()
+}
+
+// loop 是引擎的主事件/响应分发循环,直到ctx被取消为止
+func (e *Engine) loop(ctx context.Context, events, responses <-chan bus.Msg) {
 	for {
 		select {
 		case <-ctx.Done():
 			e.logger.Debug("[engine] engine stopping...")
-			return ctx.Err()
-		case event := <-e.eventChan:
-			e.logger.Debug("[engine] engine received event.", "event", event)
-			e.workerPool.Submit(func() {
-				e.processEvent(ctx, event)
-			})
-		case resp := <-e.responseChan:
-			go e.dispatcher.Dispatch(ctx, resp)
+			return
+		case msg := <-events:
+			e.handleEventMsg(ctx, msg)
+		case msg := <-responses:
+			e.handleResponseMsg(ctx, msg)
+		}
+	}
+}
+
+// shutdown 按顺序优雅关闭引擎:
+//  1. 通知适配器停止产生新事件,并等待它们真正退出
+//  2. 关闭eventChan(此时已无写入者,关闭安全)
+//  3. 在有限时间内继续消费总线上的剩余事件/响应,让已经排入eventChan/
+//     responseChan的内容被正常处理,而不是被直接丢弃
+//  4. 等待worker池处理完所有已提交任务
+//  5. 关闭responseChan(此时worker池已停,不会再有写入者)
+//  6. 等待所有go e.dispatcher.Dispatch(...)协程退出,确保不会有响应仍在
+//     发往某个适配器的同时该适配器被Close()
+func (e *Engine) shutdown(
+	cancelAdapters context.CancelFunc, adapterWG *sync.WaitGroup,
+	cancelBus context.CancelFunc, busWG *sync.WaitGroup,
+	events, responses <-chan bus.Msg,
+) {
+	e.logger.Debug("[engine] engine cleanup...")
+	defer e.logger.Debug("[engine] engine cleanup done.")
+
+	cancelAdapters()
+	adapterWG.Wait()
+	close(e.eventChan)
+
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), shutdownDrainTimeout)
+	defer cancelDrain()
+
+drainLoop:
+	for {
+		select {
+		case msg := <-events:
+			e.handleEventMsg(drainCtx, msg)
+		case msg := <-responses:
+			e.handleResponseMsg(drainCtx, msg)
+		case <-drainCtx.Done():
+			break drainLoop
+		}
+	}
+
+	e.workerPool.Stop()
+	close(e.responseChan)
+
+	cancelBus()
+	busWG.Wait()
+
+	e.dispatchWG.Wait()
+
+	for _, a := range e.adapters.GetAll() {
+		if closer, ok := a.(Closer); ok {
+			closer.Close()
+		}
+	}
+}
+
+func (e *Engine) responseTopic() string {
+	return responseTopicPrefix + e.instanceID
+}
+
+// handleEventMsg 解码一条总线事件消息并提交给worker池处理
+func (e *Engine) handleEventMsg(ctx context.Context, msg bus.Msg) {
+	var event Event
+	if err := json.Unmarshal(msg.Payload, &event); err != nil {
+		e.logger.Error("[engine] decode event failed", "error", err)
+		return
+	}
+
+	e.logger.Debug("[engine] engine received event.", "event", event)
+	err := e.workerPool.Submit(ctx, func() {
+		e.processEvent(ctx, event)
+		if msg.Ack != nil {
+			if err := msg.Ack(ctx); err != nil {
+				e.logger.Error("[engine] ack event failed", "error", err)
+			}
+		}
+	})
+	if err != nil {
+		e.logger.Debug("[engine] drop event, worker pool unavailable", "error", err)
+	}
+}
+
+// handleResponseMsg 解码一条总线响应消息并分发给本实例的适配器
+func (e *Engine) handleResponseMsg(ctx context.Context, msg bus.Msg) {
+	var resp Response
+	if err := json.Unmarshal(msg.Payload, &resp); err != nil {
+		e.logger.Error("[engine] decode response failed", "error", err)
+		return
+	}
+
+	e.dispatchWG.Add(1)
+	go func() {
+		defer e.dispatchWG.Done()
+		e.dispatcher.Dispatch(ctx, resp)
+	}()
+	if msg.Ack != nil {
+		if err := msg.Ack(ctx); err != nil {
+			e.logger.Error("[engine] ack response failed", "error", err)
+		}
+	}
+}
+
+// publishEvents 把适配器写入eventChan的事件转发到共享事件总线,并打上来源实例标记。
+// 使用独立于主循环的busCtx,以便关闭期间eventChan被排空前不会提前退出
+func (e *Engine) publishEvents(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-e.eventChan:
+			if !ok {
+				return
+			}
+			if event.OriginInstance == "" {
+				event.OriginInstance = e.instanceID
+			}
+
+			payload, err := json.Marshal(event)
+			if err != nil {
+				e.logger.Error("[engine] encode event failed", "error", err)
+				continue
+			}
+			if err := e.bus.Publish(ctx, eventsTopic, payload); err != nil {
+				e.logger.Error("[engine] publish event failed", "error", err)
+			}
+		}
+	}
+}
+
+// publishResponses 把本地产生的响应发布到响应来源实例专属的topic上,
+// 避免像广播给所有适配器那样被无关实例重复投递
+func (e *Engine) publishResponses(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case resp, ok := <-e.responseChan:
+			if !ok {
+				return
+			}
+			if resp.Metadata == nil {
+				resp.Metadata = make(map[string]string)
+			}
+			origin := resp.Metadata["origin_instance"]
+			if origin == "" {
+				origin = e.instanceID
+			}
+
+			payload, err := json.Marshal(resp)
+			if err != nil {
+				e.logger.Error("[engine] encode response failed", "error", err)
+				continue
+			}
+			if err := e.bus.Publish(ctx, responseTopicPrefix+origin, payload); err != nil {
+				e.logger.Error("[engine] publish response failed", "error", err)
+			}
 		}
 	}
 }
@@ -164,9 +419,34 @@ func (e *Engine) Run(ctx context.Context) error {
 func (e *Engine) processEvent(ctx context.Context, event Event) {
 	defer e.recycleEvent(event)
 
+	if event.Session != nil && event.Session.ID != "" {
+		if stored, err := e.sessions.Get(ctx, event.Session.ID); err == nil {
+			event.Session = stored
+		}
+	}
+
 	e.logger.Debug("[engine] engine processing event.", "event", event)
-	resp := e.processor.Process(ctx, &event)
+	resp := e.processor.Process(ctx, e.responseChan, &event)
+
+	if event.Session != nil && event.Session.ID != "" {
+		if err := e.sessions.Save(ctx, event.Session); err != nil {
+			e.logger.Error("[engine] save session failed", "session", event.Session.ID, "error", err)
+		}
+	}
+
 	if resp.Type != "" {
+		if resp.Metadata == nil {
+			resp.Metadata = make(map[string]string)
+		}
+		if _, ok := resp.Metadata["origin_instance"]; !ok {
+			resp.Metadata["origin_instance"] = event.OriginInstance
+		}
+		if _, ok := resp.Metadata["adapter"]; !ok {
+			resp.Metadata["adapter"] = event.AdapterName
+		}
+		if _, ok := resp.Metadata["request_id"]; !ok {
+			resp.Metadata["request_id"] = event.RequestID
+		}
 		e.responseChan <- *resp
 		e.logger.Debug("[engine] engine response sent.", "response", resp)
 	}
@@ -177,21 +457,6 @@ func (e *Engine) recycleEvent(event Event) {
 	e.eventPool.Put(&event)
 }
 
-// cleanup 资源清理
-func (e *Engine) cleanup() {
-	e.logger.Debug("[engine] engine cleanup...")
-	defer e.logger.Debug("[engine] engine cleanup done.")
-	close(e.eventChan)
-	close(e.responseChan)
-	e.workerPool.Stop()
-
-	for _, a := range e.adapters.GetAll() {
-		if closer, ok := a.(Closer); ok {
-			closer.Close()
-		}
-	}
-}
-
 // handleError 统一错误处理
 func (e *Engine) handleError(err error) {
 	// 实现错误上报/日志记录
@@ -228,8 +493,15 @@ func (wp *WorkerPool) Start() {
 	}
 }
 
-func (wp *WorkerPool) Submit(task func()) {
-	wp.taskChan <- task
+// Submit 提交一个任务。当任务队列已满时会等待空位或ctx被取消,
+// 避免任务队列打满时无限阻塞调用方(例如关闭期间的排空循环)
+func (wp *WorkerPool) Submit(ctx context.Context, task func()) error {
+	select {
+	case wp.taskChan <- task:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (wp *WorkerPool) Stop() {
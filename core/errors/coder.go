@@ -0,0 +1,67 @@
+// Package errors 提供一套结构化错误码体系(Coder模式),
+// 用于在命令处理结果与HTTP/WS等适配器之间传递统一、可映射的错误信息。
+package errors
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Coder 定义一个可注册的错误码
+type Coder interface {
+	Code() int       // 错误码
+	HTTPStatus() int // 对应的HTTP状态码
+	String() string  // 面向用户的错误说明
+	Reference() string
+}
+
+// unknownCode 兜底错误码,不允许被覆盖注册
+const unknownCode = 999999
+
+var (
+	mu       sync.RWMutex
+	registry = map[int]Coder{
+		unknownCode: unknownCoder{},
+	}
+)
+
+type unknownCoder struct{}
+
+func (unknownCoder) Code() int          { return unknownCode }
+func (unknownCoder) HTTPStatus() int    { return http.StatusInternalServerError }
+func (unknownCoder) String() string     { return "unknown error" }
+func (unknownCoder) Reference() string  { return "" }
+
+// Register 注册一个错误码,code为unknownCode(保留码)时panic
+func Register(coder Coder) {
+	if coder.Code() == unknownCode {
+		panic(fmt.Sprintf("errors: code %d is reserved and cannot be registered", unknownCode))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	registry[coder.Code()] = coder
+}
+
+// MustRegister 与Register相同,但要求该code尚未被注册,否则panic
+func MustRegister(coder Coder) {
+	mu.RLock()
+	_, exists := registry[coder.Code()]
+	mu.RUnlock()
+
+	if exists {
+		panic(fmt.Sprintf("errors: code %d already registered", coder.Code()))
+	}
+	Register(coder)
+}
+
+// ParseCode 按code查找已注册的Coder,未注册则返回兜底的unknown错误码
+func ParseCode(code int) Coder {
+	mu.RLock()
+	defer mu.RUnlock()
+	if c, ok := registry[code]; ok {
+		return c
+	}
+	return registry[unknownCode]
+}
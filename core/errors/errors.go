@@ -0,0 +1,80 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// codedError 将一个已注册的Coder与底层error、调用栈绑定在一起
+type codedError struct {
+	coder Coder
+	err   error
+	stack []uintptr
+}
+
+// WithCode 基于已注册的错误码包装err,并在此处捕获一次调用栈
+func WithCode(coder Coder, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codedError{coder: coder, err: err, stack: callers()}
+}
+
+// WithStack 为一个普通error附加一次调用栈快照,不归入任何已注册错误码
+func WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codedError{coder: ParseCode(unknownCode), err: err, stack: callers()}
+}
+
+// Wrap 等价于 WithCode(coder, errors.New(msg))
+func Wrap(coder Coder, msg string) error {
+	return WithCode(coder, errors.New(msg))
+}
+
+func callers() []uintptr {
+	const depth = 32
+	var pcs [depth]uintptr
+	// 跳过Callers、callers本身及捕获调用栈的构造函数这三层
+	n := runtime.Callers(3, pcs[:])
+	return pcs[:n]
+}
+
+func (e *codedError) Code() int         { return e.coder.Code() }
+func (e *codedError) HTTPStatus() int   { return e.coder.HTTPStatus() }
+func (e *codedError) Reference() string { return e.coder.Reference() }
+
+// String 面向用户的错误信息,不包含调用栈
+func (e *codedError) String() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+	return e.coder.String()
+}
+
+func (e *codedError) Error() string {
+	return e.String()
+}
+
+func (e *codedError) Unwrap() error {
+	return e.err
+}
+
+// Format 实现fmt.Formatter,%+v额外打印调用栈,仅用于日志输出
+func (e *codedError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		fmt.Fprint(s, e.String())
+		frames := runtime.CallersFrames(e.stack)
+		for {
+			frame, more := frames.Next()
+			fmt.Fprintf(s, "\n\t%s\n\t\t%s:%d", frame.Function, frame.File, frame.Line)
+			if !more {
+				break
+			}
+		}
+		return
+	}
+	fmt.Fprint(s, e.String())
+}
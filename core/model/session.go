@@ -0,0 +1,50 @@
+// Package model 存放被core与core/session都需要引用的基础数据结构。
+// 单独拆出是为了让core/session(会话存储抽象)可以依赖会话数据结构,
+// 而不必反过来依赖core包,从而避免core -> core/session -> core的导入环。
+package model
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Session 会话上下文 (跨事件状态保持)
+type Session struct {
+	ID      string
+	Values  sync.Map // 并发安全存储
+	Expires int64    // 过期时间戳
+}
+
+// sessionJSON Session的序列化形态。sync.Map本身不能直接json编解码,
+// 需要先展开成普通map,供SessionStore的Redis实现持久化
+type sessionJSON struct {
+	ID      string                 `json:"id"`
+	Values  map[string]interface{} `json:"values"`
+	Expires int64                  `json:"expires"`
+}
+
+func (s *Session) MarshalJSON() ([]byte, error) {
+	values := make(map[string]interface{})
+	s.Values.Range(func(k, v interface{}) bool {
+		if key, ok := k.(string); ok {
+			values[key] = v
+		}
+		return true
+	})
+
+	return json.Marshal(sessionJSON{ID: s.ID, Values: values, Expires: s.Expires})
+}
+
+func (s *Session) UnmarshalJSON(data []byte) error {
+	var aux sessionJSON
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	s.ID = aux.ID
+	s.Expires = aux.Expires
+	for k, v := range aux.Values {
+		s.Values.Store(k, v)
+	}
+	return nil
+}
@@ -2,14 +2,30 @@ package core
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"strconv"
 	"strings"
+	"sync/atomic"
+
+	"shirobot2/core/errors"
 )
 
+// streamChanSize 流式响应通道的缓冲大小,提供有限的背压
+const streamChanSize = 16
+
+var streamSeq int64
+
+func nextStreamID() string {
+	return fmt.Sprintf("stream-%d", atomic.AddInt64(&streamSeq, 1))
+}
+
 // 事件处理逻辑
 type EventProcessor struct {
 	logger  *slog.Logger
 	plugins *PluginManager
+
+	middlewares []Middleware // 引擎级中间件,包裹每一次命令调度
 }
 
 func NewEventProcessor(logger *slog.Logger, plugins *PluginManager) *EventProcessor {
@@ -19,6 +35,11 @@ func NewEventProcessor(logger *slog.Logger, plugins *PluginManager) *EventProces
 	}
 }
 
+// Use 追加引擎级中间件
+func (ep *EventProcessor) Use(mws ...Middleware) {
+	ep.middlewares = append(ep.middlewares, mws...)
+}
+
 func ParseCommand(input string) (args []string) {
 	if !strings.HasPrefix(input, "/") {
 		return nil
@@ -35,7 +56,9 @@ func splitCommand(input string) []string {
 	return parts
 }
 
-func (ep *EventProcessor) Process(ctx context.Context, e *Event) *Response {
+// Process 处理单个事件并返回最终响应。respChan是引擎的响应通道,
+// 供流式命令(cmd.Streaming或调用ctx.Send)在执行过程中提前下发中间响应
+func (ep *EventProcessor) Process(ctx context.Context, respChan chan<- Response, e *Event) *Response {
 	// 解析命令
 	ep.logger.Debug("[engine] try to parse command", "text", e.Data["text"])
 	args := ParseCommand(e.Data["text"].(string))
@@ -52,23 +75,100 @@ func (ep *EventProcessor) Process(ctx context.Context, e *Event) *Response {
 		return &Response{Type: ResponseTypeError, Data: "command not found"}
 	}
 
+	var seq int64
+	var streamed int32
+	streamID := nextStreamID()
+	stream := make(chan *Response, streamChanSize)
+
 	// 执行命令
 	c := Context{
 		ctx:      ctx,
 		Event:    e,
 		Response: &Response{},
 		// User:     e.User,
-		Session: e.Session,
-		Command: cmd,
+		Session:  e.Session,
+		Command:  cmd,
+		stream:   stream,
+		streamID: streamID,
+		seq:      &seq,
+		streamed: &streamed,
+	}
+
+	pumpDone := make(chan struct{})
+	go func() {
+		defer close(pumpDone)
+		for {
+			select {
+			case resp, ok := <-stream:
+				if !ok {
+					return
+				}
+				if resp.Metadata == nil {
+					resp.Metadata = make(map[string]string)
+				}
+				if _, set := resp.Metadata["origin_instance"]; !set {
+					resp.Metadata["origin_instance"] = e.OriginInstance
+				}
+				if _, set := resp.Metadata["adapter"]; !set {
+					resp.Metadata["adapter"] = e.AdapterName
+				}
+				if _, set := resp.Metadata["request_id"]; !set {
+					resp.Metadata["request_id"] = e.RequestID
+				}
+				respChan <- *resp
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// 包裹引擎级中间件(顺序: middlewares[0]最外层 -> ... -> 插件/命令级中间件 -> cmd.Handler)
+	handler := cmd.Handler
+	for i := len(ep.middlewares) - 1; i >= 0; i-- {
+		handler = ep.middlewares[i](handler)
+	}
+
+	err := handler(c, remainingArgs)
+	close(stream)
+	<-pumpDone
+
+	if err != nil {
+		return errorResponse(err)
 	}
 
-	if err := cmd.Handler(c, remainingArgs); err != nil {
-		return &Response{Type: ResponseTypeError, Data: err.Error()}
+	if cmd.Streaming || atomic.LoadInt32(&streamed) != 0 {
+		final := c.Response
+		if final == nil {
+			final = &Response{}
+		}
+		if final.Metadata == nil {
+			final.Metadata = make(map[string]string)
+		}
+		final.Metadata["stream_id"] = streamID
+		final.Metadata["seq"] = strconv.FormatInt(atomic.AddInt64(&seq, 1), 10)
+		final.Metadata["final"] = "true"
+		return final
 	}
 
 	return c.Response
 }
 
+// errorResponse 构造错误响应。若err实现了errors.Coder,将错误码、HTTP状态码和
+// 参考文档链接写入Response.Metadata,供HTTP/WS等适配器据此生成恰当的错误响应
+func errorResponse(err error) *Response {
+	resp := &Response{Type: ResponseTypeError, Data: err.Error()}
+
+	if coder, ok := err.(errors.Coder); ok {
+		resp.Metadata = map[string]string{
+			"code":        strconv.Itoa(coder.Code()),
+			"http_status": strconv.Itoa(coder.HTTPStatus()),
+			"reference":   coder.Reference(),
+		}
+	}
+
+	return resp
+}
+
 // 响应分发逻辑
 type ResponseDispatcher struct {
 	logger   *slog.Logger
@@ -82,12 +182,37 @@ func NewResponseDispatcher(logger *slog.Logger, adapters *AdapterManager) *Respo
 	}
 }
 
+// Dispatch 将响应路由给产生对应事件的那个适配器(Response.Metadata["adapter"]),
+// 而不是像过去那样广播给所有已注册的适配器。resp.Broadcast为true时保留旧的
+// 广播行为,供确实需要群发的少数场景使用;目标适配器未注册时记录日志并丢弃。
+// Dispatch本身同步发送,调用方(engine.handleResponseMsg)已经把每次调用放进了
+// 自己的协程并用WaitGroup跟踪,这里不需要再额外起协程,否则WaitGroup.Done()
+// 会在真正的SendResponse调用开始前就提前触发,起不到等待作用
 func (rd *ResponseDispatcher) Dispatch(ctx context.Context, resp Response) {
-	for _, adapter := range rd.adapters.GetAll() {
-		go func(a Adapter) {
-			if err := a.SendResponse(ctx, resp); err != nil {
-				rd.logger.Error("[engine] send response failed!", "adapter", a.Name(), "error", err)
-			}
-		}(adapter)
+	if resp.Broadcast {
+		for _, adapter := range rd.adapters.GetAll() {
+			rd.send(ctx, adapter, resp)
+		}
+		return
+	}
+
+	name := resp.Metadata["adapter"]
+	if name == "" {
+		rd.logger.Debug("[engine] response has no target adapter, dropped", "response", resp)
+		return
+	}
+
+	adapter, ok := rd.adapters.Get(name)
+	if !ok {
+		rd.logger.Error("[engine] target adapter not registered, response dropped", "adapter", name)
+		return
+	}
+
+	rd.send(ctx, adapter, resp)
+}
+
+func (rd *ResponseDispatcher) send(ctx context.Context, a Adapter, resp Response) {
+	if err := a.SendResponse(ctx, resp); err != nil {
+		rd.logger.Error("[engine] send response failed!", "adapter", a.Name(), "error", err)
 	}
 }
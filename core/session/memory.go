@@ -0,0 +1,49 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"shirobot2/core/model"
+)
+
+// MemoryStore 进程内会话存储,是单实例部署下的默认实现
+type MemoryStore struct {
+	mu   sync.RWMutex
+	data map[string]*model.Session
+}
+
+// NewMemoryStore 创建一个进程内会话存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: make(map[string]*model.Session)}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (*model.Session, error) {
+	s.mu.RLock()
+	sess, ok := s.data[id]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if sess.Expires > 0 && sess.Expires < time.Now().Unix() {
+		_ = s.Delete(ctx, id)
+		return nil, ErrNotFound
+	}
+	return sess, nil
+}
+
+func (s *MemoryStore) Save(ctx context.Context, sess *model.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[sess.ID] = sess
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, id)
+	return nil
+}
@@ -0,0 +1,71 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"shirobot2/core/model"
+)
+
+// RedisStore 基于Redis的会话存储,供多个shirobot2实例共享会话状态。
+// TTL从model.Session.Expires(Unix时间戳)换算得到
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore 创建一个Redis会话存储,prefix用于和其他业务数据隔离key空间
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStore) key(id string) string {
+	return s.prefix + id
+}
+
+func (s *RedisStore) Get(ctx context.Context, id string) (*model.Session, error) {
+	raw, err := s.client.Get(ctx, s.key(id)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("session: get %s: %w", id, err)
+	}
+
+	sess := &model.Session{}
+	if err := json.Unmarshal(raw, sess); err != nil {
+		return nil, fmt.Errorf("session: decode %s: %w", id, err)
+	}
+	return sess, nil
+}
+
+func (s *RedisStore) Save(ctx context.Context, sess *model.Session) error {
+	var ttl time.Duration
+	if sess.Expires > 0 {
+		ttl = time.Until(time.Unix(sess.Expires, 0))
+		if ttl <= 0 {
+			return s.Delete(ctx, sess.ID)
+		}
+	}
+
+	raw, err := json.Marshal(sess)
+	if err != nil {
+		return fmt.Errorf("session: encode %s: %w", sess.ID, err)
+	}
+
+	if err := s.client.Set(ctx, s.key(sess.ID), raw, ttl).Err(); err != nil {
+		return fmt.Errorf("session: save %s: %w", sess.ID, err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	if err := s.client.Del(ctx, s.key(id)).Err(); err != nil {
+		return fmt.Errorf("session: delete %s: %w", id, err)
+	}
+	return nil
+}
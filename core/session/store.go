@@ -0,0 +1,20 @@
+// Package session 提供会话的持久化存储抽象,取代原先基于sync.Pool的临时对象池,
+// 使会话状态可以在多个shirobot2实例间共享
+package session
+
+import (
+	"context"
+	"errors"
+
+	"shirobot2/core/model"
+)
+
+// ErrNotFound 表示请求的会话不存在(或已过期)
+var ErrNotFound = errors.New("session: not found")
+
+// Store 会话存储抽象。Save以model.Session.Expires换算过期时间(0表示永不过期)
+type Store interface {
+	Get(ctx context.Context, id string) (*model.Session, error)
+	Save(ctx context.Context, sess *model.Session) error
+	Delete(ctx context.Context, id string) error
+}
@@ -1,6 +1,8 @@
 package core
 
-import "sync"
+import (
+	"shirobot2/core/model"
+)
 
 // Event 基础数据结构定义
 type Event struct {
@@ -8,6 +10,16 @@ type Event struct {
 	Platform string                 // 来源平台: wechat/slack...
 	Data     map[string]interface{} // 原始数据
 	Session  *Session               // 会话上下文
+
+	// AdapterName 记录产生该事件的适配器实例名,用于把处理结果路由回同一个适配器,
+	// 而不是广播给所有已注册的适配器
+	AdapterName string
+	// OriginInstance 产生该事件的shirobot2实例id,用于分布式部署下
+	// 把处理结果路由回发起请求的那个实例,而不是广播给所有副本
+	OriginInstance string
+	// RequestID 由适配器生成的请求关联key,用于把该事件产生的所有响应
+	// (含流式分片)关联回适配器侧发起该请求的那一次调用,而不是依赖到达顺序
+	RequestID string
 }
 
 func (e Event) Reset() {
@@ -15,6 +27,9 @@ func (e Event) Reset() {
 	e.Platform = ""
 	e.Data = nil
 	e.Session = nil
+	e.AdapterName = ""
+	e.OriginInstance = ""
+	e.RequestID = ""
 }
 
 // Response 响应结构体 (统一响应格式)
@@ -22,15 +37,16 @@ type Response struct {
 	Type     string            // 响应类型: text/image...
 	Data     interface{}       // 平台特定格式数据
 	Metadata map[string]string // 元数据
-}
 
-// Session 会话上下文 (跨事件状态保持)
-type Session struct {
-	ID      string
-	Values  sync.Map // 并发安全存储
-	Expires int64    // 过期时间戳
+	// Broadcast 为true时忽略Metadata["adapter"],发给所有已注册的适配器。
+	// 默认只路由给产生对应Event的那个适配器
+	Broadcast bool
 }
 
+// Session 会话上下文 (跨事件状态保持)。类型定义实际在core/model,
+// 这里取别名是为了让core/session能够依赖会话数据结构而不必反过来依赖core,避免导入环
+type Session = model.Session
+
 // EventTypes常量
 const (
 	EventTypeMessage = "message"
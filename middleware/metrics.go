@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"shirobot2/core"
+	"shirobot2/core/errors"
+)
+
+var (
+	commandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "shirobot2",
+		Subsystem: "command",
+		Name:      "duration_seconds",
+		Help:      "命令处理耗时分布",
+	}, []string{"command"})
+
+	commandTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "shirobot2",
+		Subsystem: "command",
+		Name:      "total",
+		Help:      "命令处理总次数,按错误码分类",
+	}, []string{"command", "code"})
+)
+
+func init() {
+	prometheus.MustRegister(commandDuration, commandTotal)
+}
+
+// Metrics 为每次命令调度记录耗时直方图与按命令名/错误码分类的计数器
+func Metrics() core.Middleware {
+	return func(next core.CommandHandler) core.CommandHandler {
+		return func(ctx core.Context, args []string) error {
+			start := time.Now()
+			err := next(ctx, args)
+
+			name := commandName(ctx)
+			commandDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+			commandTotal.WithLabelValues(name, errorCode(err)).Inc()
+
+			return err
+		}
+	}
+}
+
+func commandName(ctx core.Context) string {
+	if ctx.Command != nil {
+		return ctx.Command.Name
+	}
+	return "unknown"
+}
+
+func errorCode(err error) string {
+	if err == nil {
+		return "0"
+	}
+	if coder, ok := err.(errors.Coder); ok {
+		return strconv.Itoa(coder.Code())
+	}
+	return "unknown"
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"shirobot2/core"
+)
+
+const (
+	bucketGCInterval = time.Minute
+	bucketIdleTTL    = 10 * time.Minute
+)
+
+// bucket 单个会话的令牌桶及其最近活跃时间(供GC判断是否回收)
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimit 按Event.Session.ID对每个会话做令牌桶限流,perUserQPS为每秒放行速率,
+// burst为桶容量。后台goroutine周期性回收长时间空闲的令牌桶,避免map无限增长
+func RateLimit(perUserQPS float64, burst int) core.Middleware {
+	buckets := &bucketMap{m: make(map[string]*bucket)}
+	go buckets.gcLoop()
+
+	return func(next core.CommandHandler) core.CommandHandler {
+		return func(ctx core.Context, args []string) error {
+			key := ""
+			if ctx.Session != nil {
+				key = ctx.Session.ID
+			}
+
+			if !buckets.allow(key, perUserQPS, burst) {
+				return fmt.Errorf("rate limit exceeded")
+			}
+
+			return next(ctx, args)
+		}
+	}
+}
+
+type bucketMap struct {
+	mu sync.Mutex
+	m  map[string]*bucket
+}
+
+func (bm *bucketMap) allow(key string, qps float64, burst int) bool {
+	bm.mu.Lock()
+	b, ok := bm.m[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(qps), burst)}
+		bm.m[key] = b
+	}
+	b.lastSeen = time.Now()
+	bm.mu.Unlock()
+
+	return b.limiter.Allow()
+}
+
+func (bm *bucketMap) gcLoop() {
+	ticker := time.NewTicker(bucketGCInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cutoff := time.Now().Add(-bucketIdleTTL)
+
+		bm.mu.Lock()
+		for key, b := range bm.m {
+			if b.lastSeen.Before(cutoff) {
+				delete(bm.m, key)
+			}
+		}
+		bm.mu.Unlock()
+	}
+}
@@ -0,0 +1,31 @@
+// Package middleware 提供可直接挂载到core.Engine或core.CommandRegistry的
+// 生产可用中间件实现
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+
+	"shirobot2/core"
+)
+
+// Recover 捕获命令处理过程中的panic,转换为error以产出ResponseTypeError响应,
+// 避免单个命令的panic打垮worker
+func Recover(logger *slog.Logger) core.Middleware {
+	return func(next core.CommandHandler) core.CommandHandler {
+		return func(ctx core.Context, args []string) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Error("[middleware.recover] panic recovered",
+						"panic", r,
+						"stack", string(debug.Stack()),
+					)
+					err = fmt.Errorf("internal error: %v", r)
+				}
+			}()
+
+			return next(ctx, args)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"shirobot2/core"
+)
+
+// timeoutGrace 超时发生后,允许仍在运行的handler协程真正退出的宽限期。
+// EventProcessor.Process在handler返回后立即close(stream),若Timeout在handler
+// 协程仍运行时就返回,该协程后续调用ctx.Send()会写入已关闭的channel并panic;
+// 这里等待它退出(或宽限期耗尽)后再返回。handler在宽限期内退出时,"handler返回即已
+// 真正停止"这个约定成立;宽限期耗尽时照样返回,此时handler协程可能仍在运行
+const timeoutGrace = 2 * time.Second
+
+// Timeout 为命令处理设置最长执行时间,超时后取消Context.ctx并返回context.DeadlineExceeded
+func Timeout(d time.Duration) core.Middleware {
+	return func(next core.CommandHandler) core.CommandHandler {
+		return func(ctx core.Context, args []string) error {
+			c, cancel := context.WithTimeout(ctx.Context(), d)
+			defer cancel()
+			ctx = ctx.WithContext(c)
+
+			done := make(chan error, 1)
+			go func() { done <- next(ctx, args) }()
+
+			select {
+			case err := <-done:
+				return err
+			case <-c.Done():
+			}
+
+			select {
+			case <-done:
+			case <-time.After(timeoutGrace):
+			}
+			return c.Err()
+		}
+	}
+}